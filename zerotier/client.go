@@ -8,11 +8,19 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 )
 
 type ZeroTierClient struct {
 	ApiKey     string
 	Controller string
+
+	// errors when the API returns fields not modeled by the provider
+	StrictApi bool
+
+	// networks read during this run, shared by the members of the same network
+	networksMutex sync.Mutex
+	networks      map[string]*Network
 }
 
 type Route struct {
@@ -61,6 +69,25 @@ type ConfigReadOnly struct {
 	CreationTime int64 `json:"creationTime"`
 	LastModified int64 `json:"lastModified"`
 	Revision     int   `json:"revision"`
+
+	// not managed by the provider, only modeled so strict_api accepts them
+	Id                    string        `json:"id"`
+	Nwid                  string        `json:"nwid"`
+	Objtype               string        `json:"objtype"`
+	Mtu                   int           `json:"mtu"`
+	DNS                   *DNS          `json:"dns"`
+	AuthTokens            []interface{} `json:"authTokens"`
+	AuthorizationEndpoint string        `json:"authorizationEndpoint"`
+	ClientId              string        `json:"clientId"`
+	SsoEnabled            bool          `json:"ssoEnabled"`
+	SsoConfig             interface{}   `json:"ssoConfig"`
+	RemoteTraceLevel      int           `json:"remoteTraceLevel"`
+	RemoteTraceTarget     *string       `json:"remoteTraceTarget"`
+}
+
+type DNS struct {
+	Domain  string   `json:"domain"`
+	Servers []string `json:"servers"`
 }
 
 type Network struct {
@@ -77,6 +104,16 @@ type NetworkReadOnly struct {
 	Config             *ConfigReadOnly      `json:"config"`
 	TagsByName         map[string]TagByName `json:"tagsByName"`
 	CapabilitiesByName map[string]int       `json:"capabilitiesByName"`
+
+	// not managed by the provider, only modeled so strict_api accepts them
+	Type                  string                 `json:"type"`
+	Clock                 int64                  `json:"clock"`
+	OwnerId               string                 `json:"ownerId"`
+	OnlineMemberCount     int                    `json:"onlineMemberCount"`
+	AuthorizedMemberCount int                    `json:"authorizedMemberCount"`
+	TotalMemberCount      int                    `json:"totalMemberCount"`
+	Permissions           map[string]interface{} `json:"permissions"`
+	UI                    map[string]interface{} `json:"ui"`
 }
 
 type Capability struct {
@@ -111,6 +148,22 @@ type Member struct {
 	Hidden             bool          `json:"hidden"`
 	Config             *MemberConfig `json:"config"`
 }
+type MemberReadOnly struct {
+	Member
+
+	// not managed by the provider, only modeled so strict_api accepts them
+	Type                string      `json:"type"`
+	Clock               int64       `json:"clock"`
+	ControllerId        string      `json:"controllerId"`
+	LastOnline          int64       `json:"lastOnline"`
+	LastSeen            int64       `json:"lastSeen"`
+	Online              bool        `json:"online"`
+	PhysicalAddress     *string     `json:"physicalAddress"`
+	PhysicalLocation    interface{} `json:"physicalLocation"`
+	ClientVersion       string      `json:"clientVersion"`
+	ProtocolVersion     int         `json:"protocolVersion"`
+	SupportsRulesEngine bool        `json:"supportsRulesEngine"`
+}
 type MemberConfig struct {
 	Authorized      bool     `json:"authorized"`
 	Capabilities    []int    `json:"capabilities"`
@@ -118,6 +171,10 @@ type MemberConfig struct {
 	ActiveBridge    bool     `json:"activeBridge"`
 	NoAutoAssignIps bool     `json:"noAutoAssignIps"`
 	IpAssignments   []string `json:"ipAssignments"`
+
+	// only filled when reading from the API
+	// left nil when posting, so it is not sent
+	*MemberConfigReadOnly
 }
 type MemberConfigReadOnly struct {
 	CreationTime       int `json:"creationTime"`
@@ -126,6 +183,24 @@ type MemberConfigReadOnly struct {
 	VMinor             int `json:"vMinor"`
 	VRev               int `json:"vRev"`
 	VProto             int `json:"vProto"`
+
+	// not managed by the provider, only modeled so strict_api accepts them
+	Id                       string  `json:"id"`
+	Nwid                     string  `json:"nwid"`
+	Objtype                  string  `json:"objtype"`
+	Address                  string  `json:"address"`
+	Identity                 string  `json:"identity"`
+	Revision                 int     `json:"revision"`
+	LastDeauthorizedTime     int     `json:"lastDeauthorizedTime"`
+	AuthenticationExpiryTime int64   `json:"authenticationExpiryTime"`
+	SsoExempt                bool    `json:"ssoExempt"`
+	RemoteTraceLevel         int     `json:"remoteTraceLevel"`
+	RemoteTraceTarget        *string `json:"remoteTraceTarget"`
+}
+
+type Status struct {
+	Id      string `json:"id"`
+	Version string `json:"version"`
 }
 
 func CIDRToRange(cidr string) (net.IP, net.IP, error) {
@@ -197,6 +272,20 @@ func (s *ZeroTierClient) doRequest(reqName string, req *http.Request) ([]byte, e
 	return body, nil
 }
 
+// Check the API response against the structs modeling it, rejecting unknown fields when running on strict mode
+// Only used when reading, as a create must not fail after the controller already stored the object
+func (s *ZeroTierClient) checkStrict(reqName string, body []byte, model interface{}) error {
+	if !s.StrictApi {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(model); err != nil {
+		return fmt.Errorf("%s received a response not handled by the provider (strict_api): %s", reqName, err)
+	}
+	return nil
+}
+
 func (s *ZeroTierClient) headRequest(req *http.Request) (*http.Response, error) {
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", s.ApiKey))
 	client := &http.Client{}
@@ -236,14 +325,44 @@ func (client *ZeroTierClient) GetNetwork(id string) (*Network, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = client.checkStrict("GetNetwork", bytes, &NetworkReadOnly{})
+	if err != nil {
+		return nil, err
+	}
 	var data Network
 	err = json.Unmarshal(bytes, &data)
 	if err != nil {
 		return nil, err
 	}
+	client.cacheNetwork(id, &data)
 	return &data, nil
 }
 
+// Reuse the network already read during this run, or read it from the API
+func (client *ZeroTierClient) GetCachedNetwork(id string) (*Network, error) {
+	client.networksMutex.Lock()
+	cached, ok := client.networks[id]
+	client.networksMutex.Unlock()
+	if ok {
+		return cached, nil
+	}
+	return client.GetNetwork(id)
+}
+
+// Store the network read from the API, or forget it when nil
+func (client *ZeroTierClient) cacheNetwork(id string, network *Network) {
+	client.networksMutex.Lock()
+	defer client.networksMutex.Unlock()
+	if network == nil {
+		delete(client.networks, id)
+		return
+	}
+	if client.networks == nil {
+		client.networks = map[string]*Network{}
+	}
+	client.networks[id] = network
+}
+
 func (client *ZeroTierClient) postNetwork(id string, network *Network) (*Network, error) {
 	url := strings.TrimSuffix(fmt.Sprintf(client.Controller+"/network/%s", id), "/")
 	// strip carriage returns?
@@ -256,6 +375,7 @@ func (client *ZeroTierClient) postNetwork(id string, network *Network) (*Network
 	if err != nil {
 		return nil, err
 	}
+	client.cacheNetwork(id, nil)
 	var reqName string
 	if id == "" {
 		reqName = "CreateNetwork"
@@ -288,10 +408,35 @@ func (client *ZeroTierClient) DeleteNetwork(id string) error {
 	if err != nil {
 		return err
 	}
+	client.cacheNetwork(id, nil)
 	_, err = client.doRequest("DeleteNetwork", req)
 	return err
 }
 
+////////////
+// status //
+////////////
+
+func (client *ZeroTierClient) GetStatus() (*Status, error) {
+	url := client.Controller + "/status"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := client.doRequest("GetStatus", req)
+	if err != nil {
+		return nil, err
+	}
+	// the status carries account and session details unrelated to the provider,
+	// so it is not subject to strict_api
+	var data Status
+	err = json.Unmarshal(bytes, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
 /////////////
 // members //
 /////////////
@@ -306,6 +451,10 @@ func (client *ZeroTierClient) GetMember(nwid string, nodeId string) (*Member, er
 	if err != nil {
 		return nil, err
 	}
+	err = client.checkStrict("GetMember", bytes, &MemberReadOnly{})
+	if err != nil {
+		return nil, err
+	}
 	var data Member
 	err = json.Unmarshal(bytes, &data)
 	if err != nil {
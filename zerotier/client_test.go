@@ -0,0 +1,170 @@
+package zerotier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Serve the given bodies by path, answering 404 to anything else
+// The server must be closed by the caller
+func testClient(responses map[string]string) (*ZeroTierClient, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	return &ZeroTierClient{ApiKey: "test", Controller: server.URL}, server
+}
+
+// Full responses as returned by ZeroTier Central
+const testFullNetwork = `{
+	"id": "8056c2e21c000001",
+	"type": "Network",
+	"clock": 1625000000000,
+	"config": {
+		"authTokens": [null],
+		"capabilities": [{"id": 1000, "default": false, "rules": [{"type": "ACTION_ACCEPT"}]}],
+		"creationTime": 1600000000000,
+		"dns": {"domain": "example.zt", "servers": ["10.0.96.1"]},
+		"enableBroadcast": true,
+		"id": "8056c2e21c000001",
+		"ipAssignmentPools": [{"ipRangeStart": "10.0.96.1", "ipRangeEnd": "10.0.96.254"}],
+		"lastModified": 1625000000000,
+		"mtu": 2800,
+		"multicastLimit": 32,
+		"name": "test",
+		"private": true,
+		"remoteTraceLevel": 0,
+		"remoteTraceTarget": null,
+		"revision": 12,
+		"routes": [{"target": "10.0.96.0/24", "via": null}],
+		"rules": [{"type": "ACTION_ACCEPT"}],
+		"tags": [{"id": 2000, "default": null}],
+		"v4AssignMode": {"zt": true},
+		"v6AssignMode": {"6plane": false, "rfc4193": true, "zt": false}
+	},
+	"description": "Managed by Terraform",
+	"rulesSource": "accept;",
+	"permissions": {"00000000-0000-0000-0000-000000000000": {"a": true, "d": true, "m": true, "r": true}},
+	"ownerId": "00000000-0000-0000-0000-000000000000",
+	"onlineMemberCount": 1,
+	"authorizedMemberCount": 2,
+	"totalMemberCount": 3,
+	"capabilitiesByName": {"administrator": 1000},
+	"tagsByName": {"department": {"id": 2000, "default": null, "enums": {"marketing": 100}, "flags": {}}},
+	"ui": {"membersHelpCollapsed": true, "rulesHelpCollapsed": true, "settingsHelpCollapsed": true, "v4EasyMode": true}
+}`
+
+const testFullMember = `{
+	"id": "8056c2e21c000001-a1511e5bf5",
+	"type": "Member",
+	"clock": 1625000000000,
+	"networkId": "8056c2e21c000001",
+	"nodeId": "a1511e5bf5",
+	"controllerId": "8056c2e21c",
+	"hidden": false,
+	"name": "dev machine",
+	"online": true,
+	"description": "Managed by Terraform",
+	"config": {
+		"activeBridge": false,
+		"address": "a1511e5bf5",
+		"authorized": true,
+		"capabilities": [1000],
+		"creationTime": 1600000000000,
+		"id": "a1511e5bf5",
+		"identity": "a1511e5bf5:0:0000",
+		"ipAssignments": ["10.0.96.15"],
+		"lastAuthorizedTime": 1600000000000,
+		"lastDeauthorizedTime": 0,
+		"noAutoAssignIps": false,
+		"nwid": "8056c2e21c000001",
+		"objtype": "member",
+		"remoteTraceLevel": 0,
+		"remoteTraceTarget": null,
+		"revision": 4,
+		"ssoExempt": false,
+		"authenticationExpiryTime": 0,
+		"tags": [[2000, 100]],
+		"vMajor": 1,
+		"vMinor": 6,
+		"vRev": 5,
+		"vProto": 12
+	},
+	"lastOnline": 1625000000000,
+	"lastSeen": 1625000000000,
+	"physicalAddress": "203.0.113.10",
+	"physicalLocation": null,
+	"clientVersion": "1.6.5",
+	"protocolVersion": 12,
+	"supportsRulesEngine": true,
+	"offlineNotifyDelay": 0
+}`
+
+// Add a field unknown to the provider at the top level of a response
+func withUnknownField(body string) string {
+	return strings.Replace(body, "{", `{"someNewFeature": true,`, 1)
+}
+
+func TestStrictApi(t *testing.T) {
+	responses := map[string]string{
+		"/network/8056c2e21c000001":                   testFullNetwork,
+		"/network/8056c2e21c000001/member/a1511e5bf5": testFullMember,
+		"/network/8056c2e21c000002":                   withUnknownField(testFullNetwork),
+		"/network/8056c2e21c000002/member/a1511e5bf5": withUnknownField(testFullMember),
+		"/network/8056c2e21c000003":                   strings.Replace(testFullNetwork, `"mtu": 2800,`, `"mtu": 2800, "someNewSetting": 1,`, 1),
+	}
+	client, server := testClient(responses)
+	defer server.Close()
+
+	for _, strict := range []bool{false, true} {
+		client.StrictApi = strict
+		if _, err := client.GetNetwork("8056c2e21c000001"); err != nil {
+			t.Errorf("strict %t: expected full network to be accepted, got %s", strict, err)
+		}
+		if _, err := client.GetMember("8056c2e21c000001", "a1511e5bf5"); err != nil {
+			t.Errorf("strict %t: expected full member to be accepted, got %s", strict, err)
+		}
+	}
+
+	client.StrictApi = false
+	if _, err := client.GetNetwork("8056c2e21c000002"); err != nil {
+		t.Errorf("expected unknown network field to be ignored, got %s", err)
+	}
+	if _, err := client.GetMember("8056c2e21c000002", "a1511e5bf5"); err != nil {
+		t.Errorf("expected unknown member field to be ignored, got %s", err)
+	}
+
+	client.StrictApi = true
+	for _, id := range []string{"8056c2e21c000002", "8056c2e21c000003"} {
+		_, err := client.GetNetwork(id)
+		if err == nil || !strings.Contains(err.Error(), "someNew") {
+			t.Errorf("%s: expected unknown network field to be rejected, got %v", id, err)
+		}
+	}
+	_, err := client.GetMember("8056c2e21c000002", "a1511e5bf5")
+	if err == nil || !strings.Contains(err.Error(), "someNewFeature") {
+		t.Errorf("expected unknown member field to be rejected, got %v", err)
+	}
+}
+
+func TestStrictApiDoesNotFailCreate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(withUnknownField(testFullNetwork)))
+	}))
+	defer server.Close()
+	client := &ZeroTierClient{ApiKey: "test", Controller: server.URL, StrictApi: true}
+
+	created, err := client.CreateNetwork(&Network{Config: &Config{Name: "test"}})
+	if err != nil {
+		t.Fatalf("expected create to succeed on strict mode, got %s", err)
+	}
+	if created.Id != "8056c2e21c000001" {
+		t.Errorf("expected created id to be returned, got %q", created.Id)
+	}
+}
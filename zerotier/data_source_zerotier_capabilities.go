@@ -0,0 +1,86 @@
+package zerotier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceZeroTierCapabilities() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceCapabilitiesRead,
+
+		Schema: map[string]*schema.Schema{
+			"version": {
+				Type:        schema.TypeString,
+				Description: "Version reported by the controller status.",
+				Computed:    true,
+			},
+			"rules_engine": {
+				Type:        schema.TypeBool,
+				Description: "Whether the controller supports the rules engine (rules_source, tags and capabilities). Requires 1.2.0 or later.",
+				Computed:    true,
+			},
+			"sixplane": {
+				Type:        schema.TypeBool,
+				Description: "Whether the controller supports 6PLANE addressing. Requires 1.2.0 or later.",
+				Computed:    true,
+			},
+			"dns_push": {
+				Type:        schema.TypeBool,
+				Description: "Whether the controller supports pushing DNS configuration to members. Requires 1.6.0 or later.",
+				Computed:    true,
+			},
+			"sso": {
+				Type:        schema.TypeBool,
+				Description: "Whether the controller supports SSO authentication of members. Requires 1.8.0 or later.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceCapabilitiesRead(d *schema.ResourceData, m interface{}) error {
+	client := m.(*ZeroTierClient)
+	status, err := client.GetStatus()
+	if err != nil {
+		return fmt.Errorf("unable to read controller status from API: %s", err)
+	}
+
+	// Versions where each feature was released, according to the ZeroTierOne release notes:
+	// https://github.com/zerotier/ZeroTierOne/blob/master/RELEASE-NOTES.md
+	// 1.2.0 brought the rules engine and 6PLANE, 1.6.0 DNS push and 1.8.0 SSO
+	major, minor := parseVersion(status.Version)
+	atLeast := func(wantMajor, wantMinor int) bool {
+		return major > wantMajor || (major == wantMajor && minor >= wantMinor)
+	}
+
+	d.SetId(client.Controller)
+	d.Set("version", status.Version)
+	d.Set("rules_engine", atLeast(1, 2))
+	d.Set("sixplane", atLeast(1, 2))
+	d.Set("dns_push", atLeast(1, 6))
+	d.Set("sso", atLeast(1, 8))
+
+	return nil
+}
+
+// Extract the major and minor parts of a version such as 1.8.4
+// Returns 0, 0 when the version can't be parsed, which disables every feature
+func parseVersion(version string) (int, int) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0
+	}
+	return major, minor
+}
@@ -0,0 +1,57 @@
+package zerotier
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		version string
+		major   int
+		minor   int
+	}{
+		{"1.8.4", 1, 8},
+		{"v1.6", 1, 6},
+		{"1.10.0-beta", 1, 10},
+		{"garbage", 0, 0},
+		{"", 0, 0},
+	}
+	for _, c := range cases {
+		major, minor := parseVersion(c.version)
+		if major != c.major || minor != c.minor {
+			t.Errorf("%q: expected %d.%d, got %d.%d", c.version, c.major, c.minor, major, minor)
+		}
+	}
+}
+
+func TestDataSourceCapabilities(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected map[string]bool
+	}{
+		{"1.8.4", map[string]bool{"rules_engine": true, "sixplane": true, "dns_push": true, "sso": true}},
+		{"1.6.2", map[string]bool{"rules_engine": true, "sixplane": true, "dns_push": true, "sso": false}},
+		{"1.1.14", map[string]bool{"rules_engine": false, "sixplane": false, "dns_push": false, "sso": false}},
+		{"unknown", map[string]bool{"rules_engine": false, "sixplane": false, "dns_push": false, "sso": false}},
+	}
+	for _, c := range cases {
+		client, server := testClient(map[string]string{
+			"/status": `{"id": "status", "version": "` + c.version + `", "apiVersion": "4", "user": {"id": "someone"}}`,
+		})
+		defer server.Close()
+		d := schema.TestResourceDataRaw(t, dataSourceZeroTierCapabilities().Schema, map[string]interface{}{})
+		if err := dataSourceCapabilitiesRead(d, client); err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.version, err)
+		}
+		if v := d.Get("version").(string); v != c.version {
+			t.Errorf("%s: expected version to be read, got %q", c.version, v)
+		}
+		for flag, expected := range c.expected {
+			if v := d.Get(flag).(bool); v != expected {
+				t.Errorf("%s: expected %s to be %t", c.version, flag, expected)
+			}
+		}
+	}
+}
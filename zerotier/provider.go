@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -35,7 +36,13 @@ func isValidControllerURL(i interface{}, k string) ([]string, []error) {
 	return nil, nil
 }
 
+// Provider wide defaults for resources, only known once the provider is configured
+type resourceDefaults struct {
+	OfflineNotifyDelay int
+}
+
 func Provider() terraform.ResourceProvider {
+	defaults := &resourceDefaults{}
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"api_key": {
@@ -49,17 +56,37 @@ func Provider() terraform.ResourceProvider {
 				DefaultFunc:  schema.EnvDefaultFunc("ZEROTIER_CONTROLLER_URL", "https://my.zerotier.com/api"),
 				ValidateFunc: isValidControllerURL,
 			},
+			"default_offline_notify_delay": {
+				Type:         schema.TypeInt,
+				Description:  "Default offline_notify_delay (milliseconds) applied to members which don't set the attribute.",
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"strict_api": {
+				Type:        schema.TypeBool,
+				Description: "Error when reading networks or members returns fields which are not handled by the provider, to detect API changes early.",
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("ZEROTIER_STRICT_API", false),
+			},
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"zerotier_capabilities": dataSourceZeroTierCapabilities(),
 		},
 		ResourcesMap: map[string]*schema.Resource{
 			"zerotier_network": resourceZeroTierNetwork(),
-			"zerotier_member":  resourceZeroTierMember(),
+			"zerotier_member":  resourceZeroTierMember(defaults),
+		},
+		ConfigureFunc: func(d *schema.ResourceData) (interface{}, error) {
+			return configureProvider(d, defaults)
 		},
-		ConfigureFunc: configureProvider,
 	}
 }
 
-func configureProvider(d *schema.ResourceData) (interface{}, error) {
+func configureProvider(d *schema.ResourceData, defaults *resourceDefaults) (interface{}, error) {
+	defaults.OfflineNotifyDelay = d.Get("default_offline_notify_delay").(int)
 	return &ZeroTierClient{
 		ApiKey:     d.Get("api_key").(string),
-		Controller: d.Get("controller_url").(string)}, nil
+		Controller: d.Get("controller_url").(string),
+		StrictApi:  d.Get("strict_api").(bool)}, nil
 }
@@ -1,14 +1,19 @@
 package zerotier
 
 import (
+	"bytes"
 	"fmt"
+	"log"
+	"math"
+	"net"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
-func resourceZeroTierMember() *schema.Resource {
+func resourceZeroTierMember(defaults *resourceDefaults) *schema.Resource {
 	return &schema.Resource{
 		Create: resourceMemberCreate,
 		Read:   resourceMemberRead,
@@ -18,6 +23,7 @@ func resourceZeroTierMember() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceMemberCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"network_id": {
@@ -45,15 +51,25 @@ func resourceZeroTierMember() *schema.Resource {
 				Default:  false,
 			},
 			"offline_notify_delay": {
-				Type:     schema.TypeInt,
-				Optional: true,
-				Default:  0,
+				Type:        schema.TypeInt,
+				Description: "Defaults to the provider default_offline_notify_delay when not set.",
+				Optional:    true,
+				// evaluated on plan, after the provider is configured, so unset members converge to the provider default
+				DefaultFunc: func() (interface{}, error) {
+					return defaults.OfflineNotifyDelay, nil
+				},
 			},
 			"authorized": {
 				Type:     schema.TypeBool,
 				Optional: true,
 				Default:  true,
 			},
+			"protect_gateway": {
+				Type:        schema.TypeBool,
+				Description: "Prevent de-authorizing, replacing or deleting the member while it is the via of the network default route. De-authorizing and replacing fail on plan, while deleting only fails on apply.",
+				Optional:    true,
+				Default:     false,
+			},
 			"allow_ethernet_bridging": {
 				Type:     schema.TypeBool,
 				Optional: true,
@@ -73,16 +89,24 @@ func resourceZeroTierMember() *schema.Resource {
 				},
 			},
 			"ipv4_assignments": {
-				Type:        schema.TypeSet,
-				Description: "Computed list of IPv4 assigned by ZeroTier controller assignment pool. Does not include RFC4193 nor 6PLANE addresses, only those from assignment pool or manually provided.",
+				Type:        schema.TypeList,
+				Description: "Computed list of IPv4 assigned by ZeroTier controller assignment pool, sorted by address. Does not include RFC4193 nor 6PLANE addresses, only those from assignment pool or manually provided.",
 				Computed:    true,
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
 			},
 			"ipv6_assignments": {
-				Type:        schema.TypeSet,
-				Description: "Computed list of IPv6 assigned by ZeroTier controller assignment pool. Does not include RFC4193 nor 6PLANE addresses, only those from assignment pool or manually provided.",
+				Type:        schema.TypeList,
+				Description: "Computed list of IPv6 assigned by ZeroTier controller assignment pool, sorted by address. Does not include RFC4193 nor 6PLANE addresses, only those from assignment pool or manually provided.",
+				Computed:    true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"address_families": {
+				Type:        schema.TypeList,
+				Description: "Computed list of address families (ipv4, ipv6) assigned to the member from the assignment pool or manually provided.",
 				Computed:    true,
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
@@ -98,6 +122,31 @@ func resourceZeroTierMember() *schema.Resource {
 				Description: "Computed 6PLANE (IPv6 /60) address. Always calculated and only actually assigned on the member if 6PLANE is configured on the network.",
 				Computed:    true,
 			},
+			"effective_multicast_limit": {
+				Type:        schema.TypeInt,
+				Description: "Computed multicast_limit of the network the member belongs to. Maximum number of recipients of an Ethernet multicast or broadcast sent by the member.",
+				Computed:    true,
+			},
+			"version": {
+				Type:        schema.TypeString,
+				Description: "Computed ZeroTier version (major.minor.revision) of the member, empty when not known by the controller.",
+				Computed:    true,
+			},
+			"version_major": {
+				Type:        schema.TypeInt,
+				Description: "Computed ZeroTier major version (vMajor) of the member, -1 when not known by the controller.",
+				Computed:    true,
+			},
+			"version_minor": {
+				Type:        schema.TypeInt,
+				Description: "Computed ZeroTier minor version (vMinor) of the member, -1 when not known by the controller.",
+				Computed:    true,
+			},
+			"version_revision": {
+				Type:        schema.TypeInt,
+				Description: "Computed ZeroTier revision (vRev) of the member, -1 when not known by the controller.",
+				Computed:    true,
+			},
 			"capabilities": {
 				Type:     schema.TypeSet,
 				Optional: true,
@@ -160,18 +209,81 @@ func resourceMemberDelete(d *schema.ResourceData, m interface{}) error {
 	if err != nil {
 		return err
 	}
+	if d.Get("protect_gateway").(bool) {
+		ips := d.Get("ip_assignments").(*schema.Set)
+		if err := checkGatewayProtection(client, member.NetworkId, ips, "delete"); err != nil {
+			return err
+		}
+	}
 	err = client.DeleteMember(member)
 	return err
 }
 
+func resourceMemberCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	client := m.(*ZeroTierClient)
+	if d.Id() == "" || !d.Get("protect_gateway").(bool) {
+		return nil
+	}
+	oldIps, _ := d.GetChange("ip_assignments")
+	oldNwid, _ := d.GetChange("network_id")
+	if d.HasChange("network_id") || d.HasChange("node_id") {
+		return checkGatewayProtection(client, oldNwid.(string), oldIps.(*schema.Set), "replace")
+	}
+	if !d.HasChange("authorized") || d.Get("authorized").(bool) {
+		return nil
+	}
+	return checkGatewayProtection(client, oldNwid.(string), oldIps.(*schema.Set), "de-authorize")
+}
+
+// Errors when any of the member IPs is the via of a default route on the network,
+// as removing the member would isolate the network
+func checkGatewayProtection(client *ZeroTierClient, nwid string, ips *schema.Set, action string) error {
+	network, err := client.GetCachedNetwork(nwid)
+	if err != nil {
+		return fmt.Errorf("unable to read member network from API: %s", err)
+	}
+	if network.Config == nil {
+		return nil
+	}
+	for _, r := range network.Config.Routes {
+		if r.Via == nil || !isDefaultRoute(r.Target) {
+			continue
+		}
+		if ips.Contains(*r.Via) {
+			return fmt.Errorf("refusing to %s member: it is the gateway (%s) of the default route %s and protect_gateway is enabled", action, *r.Via, r.Target)
+		}
+	}
+	return nil
+}
+
+func isDefaultRoute(target string) bool {
+	_, ipnet, err := net.ParseCIDR(target)
+	if err != nil {
+		return false
+	}
+	ones, _ := ipnet.Mask.Size()
+	return ones == 0
+}
+
 func memberFromResourceData(d *schema.ResourceData) (*Member, error) {
 	tags := d.Get("tags").(map[string]interface{})
+	// sorted, so the same tag is reported when more than one is invalid
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 	tagTuples := [][]int{}
-	for key, val := range tags {
+	for _, key := range keys {
+		val := tags[key]
 		i, err := strconv.Atoi(key)
 		if err != nil {
 			break
 		}
+		// ZeroTierOne stores tag values as uint32_t, which is wider than an int32
+		if v := int64(val.(int)); v < 0 || v > math.MaxUint32 {
+			return nil, fmt.Errorf("tag %s has value %d out of the accepted range (0 to %d)", key, v, int64(math.MaxUint32))
+		}
 		tagTuples = append(tagTuples, []int{i, val.(int)})
 	}
 	capsRaw := d.Get("capabilities").(*schema.Set).List()
@@ -251,6 +363,7 @@ func rfc4193Address(d *schema.ResourceData) string {
 
 // Split the list of assigned IPs into IPv6 and IPv4 lists
 // Does not include 6PLANE or RFC4193, only those from the assignment pool
+// Both lists are sorted by address, as the API order is not stable
 func assingnedIpsGrouping(ipAssignments []string) (ipv4s []string, ipv6s []string) {
 	for _, element := range ipAssignments {
 		if strings.Contains(element, ":") {
@@ -259,9 +372,59 @@ func assingnedIpsGrouping(ipAssignments []string) (ipv4s []string, ipv6s []strin
 			ipv4s = append(ipv4s, element)
 		}
 	}
+	sortIps(ipv4s)
+	sortIps(ipv6s)
 	return
 }
 
+// Sort a list of IPs by their parsed value, falling back to the string when not parseable
+func sortIps(ips []string) {
+	sort.SliceStable(ips, func(i, j int) bool {
+		a, b := net.ParseIP(ips[i]), net.ParseIP(ips[j])
+		if a == nil || b == nil {
+			return ips[i] < ips[j]
+		}
+		return bytes.Compare(a.To16(), b.To16()) < 0
+	})
+}
+
+// Read the member version parts, using -1 as the controller does when the member never reported its version
+func memberVersionParts(member *Member) (major int, minor int, rev int) {
+	if member.Config == nil || member.Config.MemberConfigReadOnly == nil {
+		return -1, -1, -1
+	}
+	v := member.Config.MemberConfigReadOnly
+	if v.VMajor < 0 || v.VMinor < 0 || v.VRev < 0 {
+		return -1, -1, -1
+	}
+	if v.VMajor == 0 && v.VMinor == 0 && v.VRev == 0 {
+		return -1, -1, -1
+	}
+	return v.VMajor, v.VMinor, v.VRev
+}
+
+// Format the member version as major.minor.revision
+// Empty when the member never reported its version
+func memberVersion(member *Member) string {
+	major, minor, rev := memberVersionParts(member)
+	if major < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, rev)
+}
+
+// List the address families present on the grouped IP assignments
+func addressFamilies(ipv4s []string, ipv6s []string) []string {
+	families := []string{}
+	if len(ipv4s) > 0 {
+		families = append(families, "ipv4")
+	}
+	if len(ipv6s) > 0 {
+		families = append(families, "ipv6")
+	}
+	return families
+}
+
 func resourceMemberRead(d *schema.ResourceData, m interface{}) error {
 	client := m.(*ZeroTierClient)
 
@@ -279,6 +442,12 @@ func resourceMemberRead(d *schema.ResourceData, m interface{}) error {
 		return nil
 	}
 
+	// only used for computed attributes, so the member is still read when the network can't be
+	network, err := client.GetCachedNetwork(nwid)
+	if err != nil {
+		log.Printf("[WARN] unable to read member network from API: %s", err)
+	}
+
 	ipv4Assignments, ipv6Assignments := assingnedIpsGrouping(member.Config.IpAssignments)
 
 	d.SetId(member.Id)
@@ -291,11 +460,22 @@ func resourceMemberRead(d *schema.ResourceData, m interface{}) error {
 	d.Set("authorized", member.Config.Authorized)
 	d.Set("allow_ethernet_bridging", member.Config.ActiveBridge)
 	d.Set("no_auto_assign_ips", member.Config.NoAutoAssignIps)
+	// Keep every IP known by the controller, including those added outside of Terraform (eg: on the UI),
+	// so they are reported as drift on the next plan instead of being silently ignored
 	d.Set("ip_assignments", member.Config.IpAssignments)
 	d.Set("ipv4_assignments", ipv4Assignments)
 	d.Set("ipv6_assignments", ipv6Assignments)
+	d.Set("address_families", addressFamilies(ipv4Assignments, ipv6Assignments))
 	d.Set("rfc4193_address", rfc4193Address(d))
 	d.Set("zt6plane_address", sixPlaneAddress(d))
+	if network != nil && network.Config != nil {
+		d.Set("effective_multicast_limit", network.Config.MulticastLimit)
+	}
+	major, minor, rev := memberVersionParts(member)
+	d.Set("version", memberVersion(member))
+	d.Set("version_major", major)
+	d.Set("version_minor", minor)
+	d.Set("version_revision", rev)
 	d.Set("capabilities", member.Config.Capabilities)
 	setTags(d, member)
 
@@ -0,0 +1,354 @@
+package zerotier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func testMemberResourceData(t *testing.T, raw map[string]interface{}) *schema.ResourceData {
+	config := map[string]interface{}{
+		"network_id": "8056c2e21c000001",
+		"node_id":    "a1511e5bf5",
+	}
+	for k, v := range raw {
+		config[k] = v
+	}
+	return schema.TestResourceDataRaw(t, resourceZeroTierMember(&resourceDefaults{}).Schema, config)
+}
+
+func TestDefaultOfflineNotifyDelay(t *testing.T) {
+	var posted Member
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			json.NewDecoder(r.Body).Decode(&posted)
+		}
+		w.Write([]byte(`{"id": "8056c2e21c000001-a1511e5bf5", "config": {"authorized": true}}`))
+	}))
+	defer server.Close()
+
+	p := Provider().(*schema.Provider)
+	err := p.Configure(terraform.NewResourceConfigRaw(map[string]interface{}{
+		"api_key":                      "test",
+		"controller_url":               server.URL,
+		"default_offline_notify_delay": 5000,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error configuring provider: %s", err)
+	}
+	r := p.ResourcesMap["zerotier_member"]
+
+	cases := []struct {
+		name     string
+		state    string
+		config   map[string]interface{}
+		expected string
+	}{
+		{"removed falls back to provider default", "1000", map[string]interface{}{}, "5000"},
+		{"unset converges to provider default", "0", map[string]interface{}{}, "5000"},
+		{"unset already on provider default", "5000", map[string]interface{}{}, ""},
+		{"explicit zero is kept", "1000", map[string]interface{}{"offline_notify_delay": 0}, "0"},
+	}
+	for _, c := range cases {
+		state := &terraform.InstanceState{
+			ID: "8056c2e21c000001-a1511e5bf5",
+			Attributes: map[string]string{
+				"network_id":           "8056c2e21c000001",
+				"node_id":              "a1511e5bf5",
+				"description":          "Managed by Terraform",
+				"authorized":           "true",
+				"offline_notify_delay": c.state,
+			},
+		}
+		config := map[string]interface{}{
+			"network_id": "8056c2e21c000001",
+			"node_id":    "a1511e5bf5",
+		}
+		for k, v := range c.config {
+			config[k] = v
+		}
+
+		diff, err := r.Diff(state, terraform.NewResourceConfigRaw(config), p.Meta())
+		if err != nil {
+			t.Fatalf("%s: unexpected error planning: %s", c.name, err)
+		}
+		attr := diff.Attributes["offline_notify_delay"]
+		if c.expected == "" {
+			if attr != nil {
+				t.Errorf("%s: expected no change, got %#v", c.name, attr)
+			}
+			continue
+		}
+		if attr == nil || attr.New != c.expected {
+			t.Fatalf("%s: expected plan to %s, got %#v", c.name, c.expected, attr)
+		}
+
+		posted = Member{}
+		if _, err := r.Apply(state, diff, p.Meta()); err != nil {
+			t.Fatalf("%s: unexpected error applying: %s", c.name, err)
+		}
+		if sent := strconv.Itoa(posted.OfflineNotifyDelay); sent != c.expected {
+			t.Errorf("%s: expected %s to be sent to the API, got %s", c.name, c.expected, sent)
+		}
+	}
+}
+
+func TestAssignedIpsGroupingSorted(t *testing.T) {
+	ipv4s, ipv6s := assingnedIpsGrouping([]string{
+		"10.0.96.20",
+		"fd00::10",
+		"10.0.96.3",
+		"192.168.1.1",
+		"fd00::2",
+		"10.0.96.100",
+	})
+
+	expectedV4 := []string{"10.0.96.3", "10.0.96.20", "10.0.96.100", "192.168.1.1"}
+	if !reflect.DeepEqual(ipv4s, expectedV4) {
+		t.Errorf("expected %v, got %v", expectedV4, ipv4s)
+	}
+	expectedV6 := []string{"fd00::2", "fd00::10"}
+	if !reflect.DeepEqual(ipv6s, expectedV6) {
+		t.Errorf("expected %v, got %v", expectedV6, ipv6s)
+	}
+}
+
+func TestAddressFamilies(t *testing.T) {
+	cases := []struct {
+		name     string
+		ips      []string
+		expected []string
+	}{
+		{"dual-stack", []string{"fd00::2", "10.0.96.3"}, []string{"ipv4", "ipv6"}},
+		{"ipv4 only", []string{"10.0.96.3", "10.0.96.4"}, []string{"ipv4"}},
+		{"ipv6 only", []string{"fd00::2"}, []string{"ipv6"}},
+		{"no assignments", []string{}, []string{}},
+	}
+	for _, c := range cases {
+		ipv4s, ipv6s := assingnedIpsGrouping(c.ips)
+		if families := addressFamilies(ipv4s, ipv6s); !reflect.DeepEqual(families, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, families)
+		}
+	}
+}
+
+func TestEffectiveMulticastLimit(t *testing.T) {
+	networkReads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/network/8056c2e21c000001":
+			networkReads++
+			w.Write([]byte(`{"id": "8056c2e21c000001", "config": {"name": "test", "multicastLimit": 64}}`))
+		case "/network/8056c2e21c000001/member/a1511e5bf5", "/network/8056c2e21c000001/member/b1511e5bf5":
+			w.Write([]byte(`{"id": "8056c2e21c000001-a1511e5bf5", "config": {"authorized": true}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := &ZeroTierClient{ApiKey: "test", Controller: server.URL}
+
+	for _, nodeID := range []string{"a1511e5bf5", "b1511e5bf5"} {
+		d := testMemberResourceData(t, map[string]interface{}{"node_id": nodeID})
+		if err := resourceMemberRead(d, client); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if v := d.Get("effective_multicast_limit").(int); v != 64 {
+			t.Errorf("expected network multicast limit 64, got %d", v)
+		}
+	}
+	if networkReads != 1 {
+		t.Errorf("expected network to be read once for both members, got %d", networkReads)
+	}
+}
+
+func TestMemberReadWithoutNetwork(t *testing.T) {
+	client, server := testClient(map[string]string{
+		"/network/8056c2e21c000001/member/a1511e5bf5": `{"id": "8056c2e21c000001-a1511e5bf5", "config": {"authorized": true}}`,
+	})
+	defer server.Close()
+
+	d := testMemberResourceData(t, map[string]interface{}{})
+	if err := resourceMemberRead(d, client); err != nil {
+		t.Fatalf("expected member to be read without its network, got %s", err)
+	}
+	if !d.Get("authorized").(bool) {
+		t.Errorf("expected member to be read")
+	}
+}
+
+func TestExternalIpAssignmentDrift(t *testing.T) {
+	client, server := testClient(map[string]string{
+		"/network/8056c2e21c000001": `{"id": "8056c2e21c000001", "config": {"name": "test"}}`,
+		"/network/8056c2e21c000001/member/a1511e5bf5": `{
+			"id": "8056c2e21c000001-a1511e5bf5",
+			"networkId": "8056c2e21c000001",
+			"nodeId": "a1511e5bf5",
+			"description": "Managed by Terraform",
+			"config": {"authorized": true, "ipAssignments": ["10.0.96.15", "10.0.96.42"]}
+		}`,
+	})
+	defer server.Close()
+	config := map[string]interface{}{
+		"network_id":     "8056c2e21c000001",
+		"node_id":        "a1511e5bf5",
+		"ip_assignments": []interface{}{"10.0.96.15"},
+	}
+
+	r := resourceZeroTierMember(&resourceDefaults{})
+	d := testMemberResourceData(t, config)
+	if err := resourceMemberRead(d, client); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !d.Get("ip_assignments").(*schema.Set).Contains("10.0.96.42") {
+		t.Fatalf("expected external IP to be read into ip_assignments")
+	}
+
+	diff, err := r.Diff(d.State(), terraform.NewResourceConfigRaw(config), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff == nil || diff.Attributes["ip_assignments.#"] == nil {
+		t.Fatalf("expected external IP to be reported as drift, got %#v", diff)
+	}
+	if attr := diff.Attributes["ip_assignments.#"]; attr.Old != "2" || attr.New != "1" {
+		t.Errorf("expected external IP to be planned for removal, got %#v", attr)
+	}
+}
+
+func TestIsDefaultRoute(t *testing.T) {
+	cases := map[string]bool{
+		"0.0.0.0/0":    true,
+		"::/0":         true,
+		"10.0.96.0/24": false,
+		"fd00::/8":     false,
+		"garbage":      false,
+	}
+	for target, expected := range cases {
+		if isDefaultRoute(target) != expected {
+			t.Errorf("%s: expected %t", target, expected)
+		}
+	}
+}
+
+func testGatewayClient() (*ZeroTierClient, *httptest.Server) {
+	return testClient(map[string]string{
+		"/network/8056c2e21c000001": `{"id": "8056c2e21c000001", "config": {"name": "test", "routes": [
+			{"target": "10.0.96.0/24", "via": null},
+			{"target": "10.41.0.0/24", "via": "10.0.96.2"},
+			{"target": "0.0.0.0/0", "via": "10.0.96.1"}
+		]}}`,
+	})
+}
+
+func TestCheckGatewayProtection(t *testing.T) {
+	client, server := testGatewayClient()
+	defer server.Close()
+
+	gateway := schema.NewSet(schema.HashString, []interface{}{"10.0.96.1"})
+	if err := checkGatewayProtection(client, "8056c2e21c000001", gateway, "delete"); err == nil {
+		t.Errorf("expected the default route gateway to be protected")
+	}
+
+	other := schema.NewSet(schema.HashString, []interface{}{"10.0.96.2"})
+	if err := checkGatewayProtection(client, "8056c2e21c000001", other, "delete"); err != nil {
+		t.Errorf("expected gateways of other routes not to be protected, got %s", err)
+	}
+}
+
+func TestProtectGatewayDeAuthorize(t *testing.T) {
+	client, server := testGatewayClient()
+	defer server.Close()
+	r := resourceZeroTierMember(&resourceDefaults{})
+	state := &terraform.InstanceState{
+		ID: "8056c2e21c000001-a1511e5bf5",
+		Attributes: map[string]string{
+			"network_id":       "8056c2e21c000001",
+			"node_id":          "a1511e5bf5",
+			"authorized":       "true",
+			"protect_gateway":  "true",
+			"ip_assignments.#": "1",
+			"ip_assignments." + strconv.Itoa(schema.HashString("10.0.96.1")): "10.0.96.1",
+		},
+	}
+	config := func(protect bool) *terraform.ResourceConfig {
+		return terraform.NewResourceConfigRaw(map[string]interface{}{
+			"network_id":      "8056c2e21c000001",
+			"node_id":         "a1511e5bf5",
+			"authorized":      false,
+			"protect_gateway": protect,
+			"ip_assignments":  []interface{}{"10.0.96.1"},
+		})
+	}
+
+	if _, err := r.Diff(state, config(true), client); err == nil || !strings.Contains(err.Error(), "de-authorize") {
+		t.Errorf("expected de-authorizing the gateway to fail on plan, got %v", err)
+	}
+	if _, err := r.Diff(state, config(false), client); err != nil {
+		t.Errorf("expected de-authorizing to be planned without protection, got %s", err)
+	}
+}
+
+func TestTagValueRange(t *testing.T) {
+	d := testMemberResourceData(t, map[string]interface{}{
+		"tags": map[string]interface{}{"2000": 100, "3000": 4294967295},
+	})
+	if _, err := memberFromResourceData(d); err != nil {
+		t.Errorf("expected tags within range to be accepted, got %s", err)
+	}
+
+	d = testMemberResourceData(t, map[string]interface{}{
+		"tags": map[string]interface{}{"2000": 100, "3000": -1, "4000": 4294967296},
+	})
+	_, err := memberFromResourceData(d)
+	if err == nil || !strings.Contains(err.Error(), "tag 3000") {
+		t.Errorf("expected the first out of range tag to be reported, got %v", err)
+	}
+}
+
+func TestMemberVersion(t *testing.T) {
+	cases := []struct {
+		name     string
+		payload  string
+		expected string
+		major    int
+	}{
+		{"known version", `{"config": {"vMajor": 1, "vMinor": 4, "vRev": 6, "vProto": 10}}`, "1.4.6", 1},
+		{"without version fields", `{"config": {"authorized": true}}`, "", -1},
+		{"unknown version", `{"config": {"vMajor": -1, "vMinor": -1, "vRev": -1}}`, "", -1},
+		{"without config", `{}`, "", -1},
+	}
+	for _, c := range cases {
+		var member Member
+		if err := json.Unmarshal([]byte(c.payload), &member); err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.name, err)
+		}
+		if v := memberVersion(&member); v != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, v)
+		}
+		if major, _, _ := memberVersionParts(&member); major != c.major {
+			t.Errorf("%s: expected major %d, got %d", c.name, c.major, major)
+		}
+	}
+}
+
+func TestMemberVersionNotPosted(t *testing.T) {
+	member, err := memberFromResourceData(testMemberResourceData(t, map[string]interface{}{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	body, err := json.Marshal(member)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Contains(string(body), "vMajor") {
+		t.Errorf("expected read-only version not to be posted, got %s", body)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/hashicorp/terraform/helper/hashcode"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -34,16 +35,19 @@ func assignmentPool() *schema.Resource {
 			"cidr": {
 				Type:          schema.TypeString,
 				Optional:      true,
+				Computed:      true,
 				ConflictsWith: []string{"assignment_pool.first", "assignment_pool.last"},
 			},
 			"first": {
 				Type:          schema.TypeString,
 				Optional:      true,
+				Computed:      true,
 				ConflictsWith: []string{"assignment_pool.cidr"},
 			},
 			"last": {
 				Type:          schema.TypeString,
 				Optional:      true,
+				Computed:      true,
 				ConflictsWith: []string{"assignment_pool.cidr"},
 			},
 		},
@@ -75,8 +79,18 @@ func resourceZeroTierNetwork() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 				// pulled from ZT's default
-				Default: "#\n# Allow only IPv4, IPv4 ARP, and IPv6 Ethernet frames.\n#\ndrop\n\tnot ethertype ipv4\n\tand not ethertype arp\n\tand not ethertype ipv6\n;\n\n#\n# Uncomment to drop non-ZeroTier issued and managed IP addresses.\n#\n# This prevents IP spoofing but also blocks manual IP management at the OS level and\n# bridging unless special rules to exempt certain hosts or traffic are added before\n# this rule.\n#\n#drop\n#\tnot chr ipauth\n#;\n\n# Accept anything else. This is required since default is 'drop'.\naccept;",
-				Set:     stringHash,
+				Default:          "#\n# Allow only IPv4, IPv4 ARP, and IPv6 Ethernet frames.\n#\ndrop\n\tnot ethertype ipv4\n\tand not ethertype arp\n\tand not ethertype ipv6\n;\n\n#\n# Uncomment to drop non-ZeroTier issued and managed IP addresses.\n#\n# This prevents IP spoofing but also blocks manual IP management at the OS level and\n# bridging unless special rules to exempt certain hosts or traffic are added before\n# this rule.\n#\n#drop\n#\tnot chr ipauth\n#;\n\n# Accept anything else. This is required since default is 'drop'.\naccept;",
+				Set:              stringHash,
+				DiffSuppressFunc: rulesSourceDiffSuppress,
+			},
+			"rules_include": {
+				Type:             schema.TypeList,
+				Description:      "List of rule fragments (eg: shared macros, tags and capabilities) prepended to rules_source before being compiled by the controller.",
+				Optional:         true,
+				DiffSuppressFunc: rulesSourceDiffSuppress,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
 			},
 			"private": {
 				Type:     schema.TypeBool,
@@ -157,11 +171,12 @@ func fromResourceData(d *schema.ResourceData) (*Network, error) {
 	var routes []Route
 	for _, raw := range routesRaw {
 		r := raw.(map[string]interface{})
-		via := r["via"].(string)
-		routes = append(routes, Route{
-			Target: r["target"].(string),
-			Via:    &via,
-		})
+		route := Route{Target: r["target"].(string)}
+		// the API returns null for routes handled by the LAN, so it is sent the same way
+		if via := r["via"].(string); via != "" {
+			route.Via = &via
+		}
+		routes = append(routes, route)
 	}
 	var pools []IpRange
 	for _, raw := range d.Get("assignment_pool").(*schema.Set).List() {
@@ -179,7 +194,7 @@ func fromResourceData(d *schema.ResourceData) (*Network, error) {
 	}
 	n := &Network{
 		Id:          d.Id(),
-		RulesSource: d.Get("rules_source").(string),
+		RulesSource: expandRulesSource(d),
 		Description: d.Get("description").(string),
 		Config: &Config{
 			Name:            d.Get("name").(string),
@@ -201,6 +216,40 @@ func fromResourceData(d *schema.ResourceData) (*Network, error) {
 	return n, nil
 }
 
+func rulesIncludes(d *schema.ResourceData) string {
+	var fragments []string
+	for _, raw := range d.Get("rules_include").([]interface{}) {
+		fragments = append(fragments, raw.(string))
+	}
+	return strings.Join(fragments, "\n")
+}
+
+// Concatenate the included fragments and the rules_source, in order, to be sent for compilation
+func expandRulesSource(d *schema.ResourceData) string {
+	includes := rulesIncludes(d)
+	if includes == "" {
+		return d.Get("rules_source").(string)
+	}
+	return includes + "\n" + d.Get("rules_source").(string)
+}
+
+// Remove the included fragments from the rules returned by the API, so only the rules_source is compared.
+// If the fragments are not found, the whole source is kept so the drift is visible.
+func stripRulesIncludes(d *schema.ResourceData, source string) string {
+	includes := rulesIncludes(d)
+	if includes == "" {
+		return source
+	}
+	return strings.TrimPrefix(source, includes+"\n")
+}
+
+// Suppress rules_source and rules_include changes when the source on the state already matches
+// the configured fragments and rules_source, eg: after an import, where the state has no fragments to strip
+func rulesSourceDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	source, _ := d.GetChange("rules_source")
+	return source.(string) == expandRulesSource(d)
+}
+
 func resourceNetworkCreate(d *schema.ResourceData, m interface{}) error {
 	client := m.(*ZeroTierClient)
 	n, err := fromResourceData(d)
@@ -241,7 +290,7 @@ func resourceNetworkRead(d *schema.ResourceData, m interface{}) error {
 	d.Set("auto_assign_v6", net.Config.V6AssignMode.ZT)
 	d.Set("auto_assign_6plane", net.Config.V6AssignMode.SixPLANE)
 	d.Set("auto_assign_rfc4193", net.Config.V6AssignMode.RFC4193)
-	d.Set("rules_source", net.RulesSource)
+	d.Set("rules_source", stripRulesIncludes(d, net.RulesSource))
 
 	setRoutes(d, net)
 	setAssignmentPools(d, net)
@@ -253,7 +302,7 @@ func setAssignmentPools(d *schema.ResourceData, n *Network) {
 	rawPools := &schema.Set{F: resourceIpAssignmentHash}
 	for _, p := range n.Config.IpAssignmentPools {
 		raw := make(map[string]interface{})
-		// raw["cidr"] = SmallestCIDR(net.ParseIP(p.First), net.ParseIP(p.Last))
+		raw["cidr"] = assignmentPoolCIDR(p)
 		raw["first"] = p.First
 		raw["last"] = p.Last
 		rawPools.Add(raw)
@@ -261,6 +310,21 @@ func setAssignmentPools(d *schema.ResourceData, n *Network) {
 	d.Set("assignment_pool", rawPools)
 }
 
+// Recover the cidr of an IPv4 pool, so both cidr and first/last definitions have a clean plan after import
+// IPv6 pools and ranges which don't map to the same cidr on CIDRToRange are left empty
+func assignmentPoolCIDR(p IpRange) string {
+	first, last := net.ParseIP(p.First).To4(), net.ParseIP(p.Last).To4()
+	if first == nil || last == nil {
+		return ""
+	}
+	cidr := SmallestCIDR(first, last)
+	rangeFirst, rangeLast, err := CIDRToRange(cidr)
+	if err != nil || !rangeFirst.Equal(first) || !rangeLast.Equal(last) {
+		return ""
+	}
+	return cidr
+}
+
 func setRoutes(d *schema.ResourceData, n *Network) {
 	rawRoutes := make([]interface{}, len(n.Config.Routes))
 	for i, r := range n.Config.Routes {
@@ -0,0 +1,169 @@
+package zerotier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Simulate a `terraform import` of the network served by the client, returning the plan for the given config
+func testNetworkImportPlan(t *testing.T, client *ZeroTierClient, id string, config map[string]interface{}) *terraform.InstanceDiff {
+	r := resourceZeroTierNetwork()
+	d := r.Data(nil)
+	d.SetId(id)
+	if err := resourceNetworkRead(d, client); err != nil {
+		t.Fatalf("unexpected error reading network: %s", err)
+	}
+	diff, err := r.Diff(d.State(), terraform.NewResourceConfigRaw(config), client)
+	if err != nil {
+		t.Fatalf("unexpected error planning network: %s", err)
+	}
+	return diff
+}
+
+func TestExpandRulesSource(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceZeroTierNetwork().Schema, map[string]interface{}{
+		"name":          "test",
+		"rules_include": []interface{}{"macro allow_ssh\n\taccept dport 22;\n;", "tag department\n\tid 2000\n;"},
+		"rules_source":  "accept;",
+	})
+
+	compiled := expandRulesSource(d)
+	expected := "macro allow_ssh\n\taccept dport 22;\n;\ntag department\n\tid 2000\n;\naccept;"
+	if compiled != expected {
+		t.Errorf("expected fragments to be prepended in order, got %q", compiled)
+	}
+
+	n, err := fromResourceData(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, fragment := range []string{"macro allow_ssh", "tag department", "accept;"} {
+		if !strings.Contains(n.RulesSource, fragment) {
+			t.Errorf("expected submitted rules to contain %q, got %q", fragment, n.RulesSource)
+		}
+	}
+
+	if stripped := stripRulesIncludes(d, compiled); stripped != "accept;" {
+		t.Errorf("expected fragments to be stripped back, got %q", stripped)
+	}
+	if stripped := stripRulesIncludes(d, "drop;"); stripped != "drop;" {
+		t.Errorf("expected drifted rules to be kept, got %q", stripped)
+	}
+}
+
+func TestImportNetworkWithRulesInclude(t *testing.T) {
+	client, server := testClient(map[string]string{
+		"/network/8056c2e21c000001": `{
+			"id": "8056c2e21c000001",
+			"description": "Managed by Terraform",
+			"rulesSource": "macro allow_ssh\n\taccept dport 22;\n;\naccept;",
+			"config": {
+				"name": "test",
+				"private": true,
+				"enableBroadcast": true,
+				"multicastLimit": 32,
+				"v4AssignMode": {"zt": true},
+				"v6AssignMode": {"zt": false, "6plane": false, "rfc4193": true}
+			}
+		}`,
+	})
+	defer server.Close()
+
+	diff := testNetworkImportPlan(t, client, "8056c2e21c000001", map[string]interface{}{
+		"name":          "test",
+		"rules_include": []interface{}{"macro allow_ssh\n\taccept dport 22;\n;"},
+		"rules_source":  "accept;",
+	})
+	if !diff.Empty() {
+		t.Errorf("expected an empty plan after import, got %#v", diff.Attributes)
+	}
+
+	diff = testNetworkImportPlan(t, client, "8056c2e21c000001", map[string]interface{}{
+		"name":          "test",
+		"rules_include": []interface{}{"macro allow_http\n\taccept dport 80;\n;"},
+		"rules_source":  "accept;",
+	})
+	if diff.Empty() {
+		t.Errorf("expected changed fragments to be planned")
+	}
+}
+
+func TestImportFullyConfiguredNetwork(t *testing.T) {
+	client, server := testClient(map[string]string{
+		"/network/8056c2e21c000001": `{
+			"id": "8056c2e21c000001",
+			"description": "Imported",
+			"rulesSource": "drop not ethertype ipv4;\naccept;",
+			"config": {
+				"name": "test",
+				"private": false,
+				"enableBroadcast": false,
+				"multicastLimit": 64,
+				"v4AssignMode": {"zt": true},
+				"v6AssignMode": {"zt": true, "6plane": true, "rfc4193": false},
+				"routes": [
+					{"target": "10.0.96.0/24", "via": null},
+					{"target": "10.41.0.0/24", "via": "10.0.96.1"},
+					{"target": "fd00::/64", "via": null}
+				],
+				"ipAssignmentPools": [
+					{"ipRangeStart": "10.0.96.1", "ipRangeEnd": "10.0.96.254"},
+					{"ipRangeStart": "10.0.97.10", "ipRangeEnd": "10.0.97.20"},
+					{"ipRangeStart": "fd00::1", "ipRangeEnd": "fd00::ff"}
+				]
+			}
+		}`,
+	})
+	defer server.Close()
+
+	diff := testNetworkImportPlan(t, client, "8056c2e21c000001", map[string]interface{}{
+		"name":                "test",
+		"description":         "Imported",
+		"rules_source":        "drop not ethertype ipv4;\naccept;",
+		"private":             false,
+		"broadcast":           false,
+		"multicast_limit":     64,
+		"auto_assign_v4":      true,
+		"auto_assign_v6":      true,
+		"auto_assign_6plane":  true,
+		"auto_assign_rfc4193": false,
+		"route": []interface{}{
+			map[string]interface{}{"target": "10.0.96.0/24"},
+			map[string]interface{}{"target": "10.41.0.0/24", "via": "10.0.96.1"},
+			map[string]interface{}{"target": "fd00::/64"},
+		},
+		"assignment_pool": []interface{}{
+			map[string]interface{}{"cidr": "10.0.96.0/24"},
+			map[string]interface{}{"first": "10.0.97.10", "last": "10.0.97.20"},
+			map[string]interface{}{"first": "fd00::1", "last": "fd00::ff"},
+		},
+	})
+	if !diff.Empty() {
+		t.Errorf("expected an empty plan after import, got %#v", diff.Attributes)
+	}
+}
+
+func TestRoutesRoundTrip(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceZeroTierNetwork().Schema, map[string]interface{}{
+		"name": "test",
+		"route": []interface{}{
+			map[string]interface{}{"target": "10.0.96.0/24"},
+			map[string]interface{}{"target": "10.41.0.0/24", "via": "10.0.96.1"},
+		},
+	})
+	n, err := fromResourceData(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, r := range n.Config.Routes {
+		if r.Target == "10.0.96.0/24" && r.Via != nil {
+			t.Errorf("expected route without via to be sent as null, got %q", *r.Via)
+		}
+		if r.Target == "10.41.0.0/24" && (r.Via == nil || *r.Via != "10.0.96.1") {
+			t.Errorf("expected route via to be sent, got %v", r.Via)
+		}
+	}
+}